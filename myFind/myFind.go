@@ -5,6 +5,11 @@ Users can customize the output to focus on specific file types or extensions.
 The program resolves symbolic links, indicates broken links as [broken]
 in the output, and skips files and directories inaccessible to the current user.
 
+The directory tree is walked once, in order, but matching against each entry
+(stat calls, glob/regex matching) is fanned out over a bounded worker pool so
+multiple entries can be evaluated concurrently; an ordering goroutine reprints
+results in walk order regardless of which worker finished first.
+
 Usage:
 	myFind [options] /path/to/directory
 
@@ -13,11 +18,23 @@ Options:
 	-d: Print only directories.
 	-sl: Print only symbolic links.
 	-ext: Specify file extension to filter results (works only with -f option).
+	-name: Match the base name against a glob pattern.
+	-iname: Case-insensitive version of -name.
+	-regex: Match the full path against a regular expression.
+	-size: Match size, e.g. +1M, -500k, 10G (suffixes c/k/M/G, default c).
+	-mtime: Match modification age in days, e.g. +7, -1.
+	-mmin: Match modification age in minutes, e.g. +30, -5.
+	-maxdepth: Descend at most this many levels below the root.
+	-mindepth: Do not report entries above this many levels below the root.
+	-empty: Match empty regular files or directories.
+	-j: Number of worker goroutines evaluating predicates (default 4).
+	-print0: Separate output with NUL bytes instead of newlines.
 */
 
 package main
 
 import (
+	"bufio"
 	"errors"
 	"flag"
 	"fmt"
@@ -25,9 +42,245 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+)
+
+// Expr is a single find-style predicate evaluated against a walked entry.
+// Predicates combine with AND by default, so new ones can be added without
+// touching the walk or worker-pool code.
+type Expr interface {
+	Match(path string, info fs.FileInfo) bool
+}
+
+// andExpr matches when every sub-expression matches.
+type andExpr []Expr
+
+func (a andExpr) Match(path string, info fs.FileInfo) bool {
+	for _, e := range a {
+		if !e.Match(path, info) {
+			return false
+		}
+	}
+	return true
+}
+
+// typeExpr matches entries whose kind (file/dir/symlink) is in the allowed
+// set. With all three disabled it matches everything, mirroring myFind's
+// historical "no flags means show everything" default.
+type typeExpr struct {
+	file, dir, symlink bool
+}
+
+func (t typeExpr) Match(path string, info fs.FileInfo) bool {
+	if !t.file && !t.dir && !t.symlink {
+		return true
+	}
+	switch {
+	case info.IsDir():
+		return t.dir
+	case info.Mode()&fs.ModeSymlink != 0:
+		return t.symlink
+	case info.Mode().IsRegular():
+		return t.file
+	}
+	return false
+}
+
+// extExpr matches regular files whose name ends with one of exts.
+type extExpr []string
+
+func (e extExpr) Match(path string, info fs.FileInfo) bool {
+	if !info.Mode().IsRegular() {
+		return false
+	}
+	for _, ext := range e {
+		if strings.HasSuffix(path, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// nameExpr matches the entry's base name against a shell glob pattern.
+type nameExpr struct {
+	pattern        string
+	caseInsensitve bool
+}
+
+func (n nameExpr) Match(path string, info fs.FileInfo) bool {
+	name := info.Name()
+	pattern := n.pattern
+	if n.caseInsensitve {
+		name = strings.ToLower(name)
+		pattern = strings.ToLower(pattern)
+	}
+	ok, err := filepath.Match(pattern, name)
+	return err == nil && ok
+}
+
+// regexExpr matches the full path against a compiled regular expression.
+type regexExpr struct {
+	re *regexp.Regexp
+}
+
+func (r regexExpr) Match(path string, info fs.FileInfo) bool {
+	return r.re.MatchString(path)
+}
+
+// sizeCmp is the comparison a sizeExpr applies against a file's size.
+type sizeCmp int8
+
+const (
+	sizeExact sizeCmp = iota
+	sizeGreater
+	sizeLess
 )
 
+// sizeExpr matches regular files by byte size, the way find's -size does.
+type sizeExpr struct {
+	cmp   sizeCmp
+	bytes int64
+}
+
+func (s sizeExpr) Match(path string, info fs.FileInfo) bool {
+	if !info.Mode().IsRegular() {
+		return false
+	}
+	switch s.cmp {
+	case sizeGreater:
+		return info.Size() > s.bytes
+	case sizeLess:
+		return info.Size() < s.bytes
+	default:
+		return info.Size() == s.bytes
+	}
+}
+
+// parseSize parses a find-style -size argument: an optional leading +/-
+// comparison, a number, and an optional c/k/M/G unit suffix (bytes by
+// default).
+func parseSize(spec string) (sizeExpr, error) {
+	cmp := sizeExact
+	switch {
+	case strings.HasPrefix(spec, "+"):
+		cmp = sizeGreater
+		spec = spec[1:]
+	case strings.HasPrefix(spec, "-"):
+		cmp = sizeLess
+		spec = spec[1:]
+	}
+	if spec == "" {
+		return sizeExpr{}, fmt.Errorf("invalid -size %q", spec)
+	}
+
+	mult := int64(1)
+	switch suffix := spec[len(spec)-1]; suffix {
+	case 'c':
+		spec = spec[:len(spec)-1]
+	case 'k', 'K':
+		mult = 1 << 10
+		spec = spec[:len(spec)-1]
+	case 'm', 'M':
+		mult = 1 << 20
+		spec = spec[:len(spec)-1]
+	case 'g', 'G':
+		mult = 1 << 30
+		spec = spec[:len(spec)-1]
+	}
+
+	n, err := strconv.ParseInt(spec, 10, 64)
+	if err != nil {
+		return sizeExpr{}, fmt.Errorf("invalid -size %q: %w", spec, err)
+	}
+	return sizeExpr{cmp: cmp, bytes: n * mult}, nil
+}
+
+// timeCmp mirrors sizeCmp for relative-time predicates.
+type timeCmp int8
+
+const (
+	timeExact timeCmp = iota
+	timeOlder
+	timeNewer
+)
+
+// ageExpr matches entries by how long ago they were modified, in units of
+// unit (a day for -mtime, a minute for -mmin).
+type ageExpr struct {
+	cmp  timeCmp
+	n    int64
+	unit time.Duration
+}
+
+func (a ageExpr) Match(path string, info fs.FileInfo) bool {
+	age := time.Since(info.ModTime())
+	threshold := time.Duration(a.n) * a.unit
+	switch a.cmp {
+	case timeOlder:
+		return age > threshold
+	case timeNewer:
+		return age < threshold
+	default:
+		return age >= threshold && age < threshold+a.unit
+	}
+}
+
+// parseAge parses a find-style -mtime/-mmin argument: an optional leading
+// +/- comparison followed by an integer count of unit.
+func parseAge(spec string, unit time.Duration) (ageExpr, error) {
+	cmp := timeExact
+	switch {
+	case strings.HasPrefix(spec, "+"):
+		cmp = timeOlder
+		spec = spec[1:]
+	case strings.HasPrefix(spec, "-"):
+		cmp = timeNewer
+		spec = spec[1:]
+	}
+
+	n, err := strconv.ParseInt(spec, 10, 64)
+	if err != nil {
+		return ageExpr{}, fmt.Errorf("invalid age %q: %w", spec, err)
+	}
+	return ageExpr{cmp: cmp, n: n, unit: unit}, nil
+}
+
+// emptyExpr matches empty regular files and empty directories.
+type emptyExpr struct{}
+
+func (emptyExpr) Match(path string, info fs.FileInfo) bool {
+	if info.Mode().IsRegular() {
+		return info.Size() == 0
+	}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		return err == nil && len(entries) == 0
+	}
+	return false
+}
+
+// mindepthExpr rejects entries closer to root than min levels.
+type mindepthExpr struct {
+	root string
+	min  int
+}
+
+func (m mindepthExpr) Match(path string, info fs.FileInfo) bool {
+	return depthOf(m.root, path) >= m.min
+}
+
+// depthOf returns how many levels path is below root.
+func depthOf(root, path string) int {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return 0
+	}
+	return strings.Count(rel, string(filepath.Separator)) + 1
+}
+
 // main function is the entry point of the myFind utility.
 func main() {
 	var exts extentions
@@ -35,6 +288,17 @@ func main() {
 	fMode := flag.Bool("f", false, "Print files")
 	dirMode := flag.Bool("d", false, "Print directories")
 	flag.Var(&exts, "ext", "Specify file extension to filter results")
+	name := flag.String("name", "", "Match the base name against a glob pattern")
+	iname := flag.String("iname", "", "Case-insensitive version of -name")
+	regex := flag.String("regex", "", "Match the full path against a regular expression")
+	size := flag.String("size", "", "Match size, e.g. +1M, -500k, 10G")
+	mtime := flag.String("mtime", "", "Match modification age in days, e.g. +7, -1")
+	mmin := flag.String("mmin", "", "Match modification age in minutes, e.g. +30, -5")
+	maxdepth := flag.Int("maxdepth", -1, "Descend at most this many levels below the root")
+	mindepth := flag.Int("mindepth", 0, "Do not report entries above this many levels below the root")
+	empty := flag.Bool("empty", false, "Match empty regular files or directories")
+	jobs := flag.Int("j", 4, "Number of worker goroutines evaluating predicates")
+	print0 := flag.Bool("print0", false, "Separate output with NUL bytes instead of newlines")
 	flag.Parse()
 
 	// Validate command-line flags.
@@ -42,11 +306,6 @@ func main() {
 		CheckErr(errors.New("Need -f to specify extensions"))
 	}
 
-	// Set default flags if none are provided.
-	if !*slMode && !*fMode && !*dirMode {
-		*slMode, *fMode, *dirMode = true, true, true
-	}
-
 	// Retrieve the root directory from command-line arguments.
 	var root string
 	if len(flag.Args()) != 1 {
@@ -62,19 +321,180 @@ func main() {
 		panic(fmt.Sprintf("%s is not a directory", root))
 	}
 
-	// Walk through the directory and print entities based on the provided options.
-	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		if info.Mode()&fs.ModePerm&fs.FileMode(0400) != 0 {
-			printEntity(&path, info, dirMode, fMode, slMode, &exts)
-		} else {
-			// Print permission denied message for inaccessible files or directories.
+	exprs := andExpr{typeExpr{file: *fMode, dir: *dirMode, symlink: *slMode}}
+	if len(exts) > 0 {
+		exprs = append(exprs, extExpr(exts))
+	}
+	if *name != "" {
+		exprs = append(exprs, nameExpr{pattern: *name})
+	}
+	if *iname != "" {
+		exprs = append(exprs, nameExpr{pattern: *iname, caseInsensitve: true})
+	}
+	if *regex != "" {
+		re, err := regexp.Compile(*regex)
+		CheckErr(err)
+		exprs = append(exprs, regexExpr{re: re})
+	}
+	if *size != "" {
+		s, err := parseSize(*size)
+		CheckErr(err)
+		exprs = append(exprs, s)
+	}
+	if *mtime != "" {
+		a, err := parseAge(*mtime, 24*time.Hour)
+		CheckErr(err)
+		exprs = append(exprs, a)
+	}
+	if *mmin != "" {
+		a, err := parseAge(*mmin, time.Minute)
+		CheckErr(err)
+		exprs = append(exprs, a)
+	}
+	if *empty {
+		exprs = append(exprs, emptyExpr{})
+	}
+	if *mindepth > 0 {
+		exprs = append(exprs, mindepthExpr{root: root, min: *mindepth})
+	}
+
+	walk(root, exprs, *maxdepth, *jobs, *print0)
+}
+
+// job is a single walked entry waiting to be matched against exprs.
+type job struct {
+	seq  int
+	path string
+	d    fs.DirEntry
+}
+
+// result is a worker's verdict on a job, tagged with its original walk order
+// so the printer goroutine can emit output in that order.
+type result struct {
+	seq   int
+	path  string
+	info  fs.FileInfo
+	match bool
+}
+
+// walk traverses root with filepath.WalkDir, fanning each entry out to a
+// bounded pool of workers that evaluate exprs concurrently, and prints
+// matches through a single ordering goroutine so output isn't interleaved.
+func walk(root string, exprs Expr, maxdepth, numWorkers int, print0 bool) {
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+
+	jobs := make(chan job)
+	results := make(chan result)
+
+	var workers sync.WaitGroup
+	for i := 0; i < numWorkers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for j := range jobs {
+				results <- evalJob(j, exprs)
+			}
+		}()
+	}
+
+	go func() {
+		workers.Wait()
+		close(results)
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		printOrdered(results, print0)
+	}()
+
+	seq := 0
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			return nil
+		}
+
+		info, err := d.Info()
+		if err == nil && info.Mode()&fs.ModePerm&fs.FileMode(0400) == 0 {
 			fmt.Fprintf(os.Stderr, "%s: Permission denied\n", path)
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
 		}
+
+		if maxdepth >= 0 && depthOf(root, path) > maxdepth {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		jobs <- job{seq: seq, path: path, d: d}
+		seq++
 		return nil
 	})
+	close(jobs)
+	<-done
 	CheckErr(err)
 }
 
+// evalJob stats the walked entry and matches it against exprs.
+func evalJob(j job, exprs Expr) result {
+	info, err := j.d.Info()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return result{seq: j.seq}
+	}
+	return result{seq: j.seq, path: j.path, info: info, match: exprs.Match(j.path, info)}
+}
+
+// printOrdered buffers out-of-order results until the next expected
+// sequence number arrives, then prints in walk order.
+func printOrdered(results <-chan result, print0 bool) {
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+
+	pending := make(map[int]result)
+	next := 0
+	for r := range results {
+		pending[r.seq] = r
+		for {
+			res, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			if res.match {
+				printEntity(out, res.path, res.info, print0)
+			}
+		}
+	}
+}
+
+// printEntity prints a matched entity, resolving symlink targets.
+func printEntity(out *bufio.Writer, path string, info fs.FileInfo, print0 bool) {
+	if info.Mode()&fs.ModeSymlink != 0 && !print0 {
+		origFile, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			origFile = "[broken]"
+		}
+		fmt.Fprintf(out, "%s -> %s", path, origFile)
+	} else {
+		fmt.Fprint(out, path)
+	}
+
+	if print0 {
+		out.WriteByte(0)
+	} else {
+		out.WriteByte('\n')
+	}
+}
+
 // CheckErr is a utility function to check for and handle errors.
 func CheckErr(err error) {
 	if err != nil {
@@ -101,31 +521,3 @@ func (e *extentions) Set(val string) error {
 
 // validExt is a utility function to validate file extensions using regular expressions.
 var validExt = regexp.MustCompile(`^[a-zA-Z0-9]+$`).MatchString
-
-// printEntity prints the entity (file, directory, or symbolic link) based on the specified options.
-func printEntity(path *string, info os.FileInfo, dirMode, fMode, slMode *bool, exts *extentions) {
-	if info.IsDir() && *dirMode {
-		fmt.Println(*path)
-	} else if info.Mode().IsRegular() && *fMode {
-		if len(*exts) > 0 {
-			processExt(path, exts)
-		} else {
-			fmt.Println(*path)
-		}
-	} else if info.Mode()&fs.ModeSymlink != 0 && *slMode {
-		origFile, err := filepath.EvalSymlinks(info.Name())
-		if err != nil {
-			origFile = "[broken]"
-		}
-		fmt.Printf("%s -> %s\n", *path, origFile)
-	}
-}
-
-// processExt prints the entity if it matches any of the specified file extensions.
-func processExt(path *string, exts *extentions) {
-	for _, ext := range *exts {
-		if strings.HasSuffix(*path, ext) {
-			fmt.Println(*path)
-		}
-	}
-}
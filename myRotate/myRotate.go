@@ -1,162 +1,924 @@
 /*
 myRotate is a utility to archive .log files into compressed tar archives.
-The archive command allows archiving multiple .log files into compressed tar archives (.tar.gz). 
-It takes a destination directory as input where the archived files will be stored. 
+The archive command allows archiving multiple .log files into compressed tar archives (.tar.gz).
+It takes a destination directory as input where the archived files will be stored.
 If no destination is provided, the archived files will be stored in the current directory.
 
+Beyond a plain one-shot archive, myRotate supports logrotate-style policies: a file
+is only rotated once it crosses a size or age threshold, old archives beyond a
+configured count are pruned, and the source file can be truncated in place after
+it has been safely archived. A -config file lets many globs be rotated, each with
+its own policy, in a single invocation.
+
+The archive itself is written through an ArchiveWriter, so the format is
+pluggable: gzip-tar, zstd-tar, xz-tar, plain zip, or a classic Unix ar archive
+(handy for bundling many small .log files the way `go tool pack` bundles
+objects).
+
+For logs that get rotated repeatedly with mostly-identical content (daily
+cron jobs), -dedup DIR splits the file into content-addressed chunks stored
+once under DIR and writes only a small manifest tar entry per rotation;
+-restore reverses this, reassembling the original file from the manifest and
+the chunk store, verifying each chunk's digest as it goes.
+
 Usage:
-	archive -a path/to/archive/destination [file1 file2 ...]
+
+	myRotate -a path/to/archive/destination [options] [file1 file2 ...]
+	myRotate -config path/to/rotate.json
+	myRotate -dedup path/to/chunk/store -a path/to/archive/destination [file1 file2 ...]
+	myRotate -dedup path/to/chunk/store -restore archive.tar dest.log
 
 Options:
-  -a string
-        path/to/archive/destination
+
+	-a string
+	      path/to/archive/destination
+	-size string
+	      rotate only if the file is at least this big, e.g. 1M, 500K
+	-age string
+	      rotate only if the file is at least this old, e.g. 24h, 30m
+	-keep int
+	      retain only the N most recent archives per base name in the destination
+	-truncate
+	      truncate the source file in place after it has been archived
+	-format string
+	      archive format: targz, tarzst, tarxz, zip, or ar (default "targz")
+	-level int
+	      compressor level, format-dependent (0 means the format's default)
+	-concurrency int
+	      maximum number of files archived at once (default 4)
+	-config string
+	      path to a JSON file describing multiple globs and per-glob policies
+	-dedup string
+	      content-addressed chunk store directory; rotations write a manifest
+	      instead of raw bytes and reuse chunks already present in the store
+	-restore
+	      reassemble a -dedup archive: myRotate -dedup DIR -restore archive dest
 */
 package main
 
 import (
 	"archive/tar"
+	"archive/zip"
+	"compress/flate"
 	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"io/fs"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
 )
 
+// Rotator archives a single .log file according to a size/age/count/truncate
+// policy, mirroring the subset of logrotate's behavior myRotate needs.
+type Rotator struct {
+	Dest        string        // destination directory for completed archives, "" keeps them in place
+	MaxSize     int64         // rotate once the file is at least this many bytes, 0 disables the check
+	MaxAge      time.Duration // rotate once the file is at least this old, 0 disables the check
+	Keep        int           // retain only the N most recent archives per base name, 0 disables pruning
+	Truncate    bool          // truncate the source file in place once it has been archived
+	Format      string        // archive format, see newArchiveWriter
+	Level       int           // compressor level, 0 means the format's default
+	Concurrency int           // max files archived at once, <=1 means sequential
+	Dedup       *ChunkStore   // when set, rotations write a chunk manifest instead of raw bytes
+}
+
+// Rotate archives path if it meets the Rotator's policy, then applies
+// pruning and truncation. It is a no-op, returning nil, when the Rotator has
+// a size or age policy configured and path does not meet it.
+func (r *Rotator) Rotate(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if !r.shouldRotate(info) {
+		return nil
+	}
+
+	ext := extensionFor(r.Format)
+	if r.Dedup != nil {
+		ext = ".tar"
+	}
+	name, ok := getArchiveName(path, info, ext)
+	if !ok {
+		return fmt.Errorf("%s: wrong file format, only .log accepted", path)
+	}
+
+	archive, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+
+	if r.Dedup != nil {
+		err = writeDedupArchive(path, archive, info, r.Dedup)
+	} else {
+		err = fillArchive(path, archive, info, r.Format, r.Level)
+	}
+	if err != nil {
+		archive.Close()
+		os.Remove(name)
+		return err
+	}
+	archive.Close()
+	fmt.Println("ARCHIVED:", path, "->", name)
+
+	base := filepath.Base(name)
+	if r.Dest != "" {
+		if err := moveArchive(name, r.Dest); err != nil {
+			return err
+		}
+	}
+
+	if r.Truncate {
+		if err := truncateFile(path); err != nil {
+			return err
+		}
+	}
+
+	if r.Keep > 0 {
+		dir := r.Dest
+		if dir == "" {
+			dir = filepath.Dir(path)
+		}
+		if err := pruneArchives(dir, archiveBaseName(base, ext), ext, r.Keep); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RotateAll archives every path, running at most r.Concurrency archives at
+// once (at least 1), and prints any per-file error to stderr rather than
+// aborting the rest of the batch.
+func (r *Rotator) RotateAll(paths []string) {
+	concurrency := r.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+
+	wg := new(sync.WaitGroup)
+	for _, path := range paths {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(path string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := r.Rotate(path); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}(path)
+	}
+	wg.Wait()
+}
+
+// shouldRotate reports whether info meets the Rotator's size or age policy.
+// With neither policy configured, every call rotates, matching myRotate's
+// historical unconditional-archive behavior.
+func (r *Rotator) shouldRotate(info fs.FileInfo) bool {
+	if r.MaxSize <= 0 && r.MaxAge <= 0 {
+		return true
+	}
+	if r.MaxSize > 0 && info.Size() >= r.MaxSize {
+		return true
+	}
+	if r.MaxAge > 0 && time.Since(info.ModTime()) >= r.MaxAge {
+		return true
+	}
+	return false
+}
+
+// policyConfig is the shape of a -config file: one policy per glob pattern.
+type policyConfig struct {
+	Globs []globPolicy `json:"globs"`
+}
+
+// globPolicy describes how to rotate every file matched by Pattern.
+type globPolicy struct {
+	Pattern     string `json:"pattern"`
+	Dest        string `json:"dest"`
+	Size        string `json:"size"`
+	Age         string `json:"age"`
+	Keep        int    `json:"keep"`
+	Truncate    bool   `json:"truncate"`
+	Format      string `json:"format"`
+	Level       int    `json:"level"`
+	Concurrency int    `json:"concurrency"`
+	Dedup       string `json:"dedup"`
+}
+
+// loadConfig reads and parses a -config file.
+func loadConfig(path string) (*policyConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg policyConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// rotatorFor builds a Rotator from a globPolicy, parsing its size and age fields.
+func rotatorFor(p globPolicy) (*Rotator, error) {
+	var maxSize int64
+	var err error
+	if p.Size != "" {
+		maxSize, err = parseSize(p.Size)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var maxAge time.Duration
+	if p.Age != "" {
+		maxAge, err = time.ParseDuration(p.Age)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rotator := &Rotator{
+		Dest:        p.Dest,
+		MaxSize:     maxSize,
+		MaxAge:      maxAge,
+		Keep:        p.Keep,
+		Truncate:    p.Truncate,
+		Format:      p.Format,
+		Level:       p.Level,
+		Concurrency: p.Concurrency,
+	}
+	if p.Dedup != "" {
+		rotator.Dedup = NewChunkStore(p.Dedup)
+	}
+	return rotator, nil
+}
+
+// parseSize parses a byte count with an optional K/M/G suffix (e.g. "1M",
+// "500K") into a number of bytes. Suffixes are binary (1K == 1024 bytes).
+func parseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("empty size")
+	}
+
+	mult := int64(1)
+	switch suffix := s[len(s)-1]; suffix {
+	case 'k', 'K':
+		mult = 1 << 10
+		s = s[:len(s)-1]
+	case 'm', 'M':
+		mult = 1 << 20
+		s = s[:len(s)-1]
+	case 'g', 'G':
+		mult = 1 << 30
+		s = s[:len(s)-1]
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %w", s, err)
+	}
+	return n * mult, nil
+}
+
 // main is the entry point of the program.
 func main() {
 	// Parse command line flags
 	dest := flag.String("a", "", "path/to/archive/destination")
+	sizeFlag := flag.String("size", "", "rotate only if the file is at least this big, e.g. 1M, 500K")
+	ageFlag := flag.String("age", "", "rotate only if the file is at least this old, e.g. 24h, 30m")
+	keep := flag.Int("keep", 0, "retain only the N most recent archives per base name")
+	truncate := flag.Bool("truncate", false, "truncate the source file in place after archiving")
+	format := flag.String("format", "targz", "archive format: targz, tarzst, tarxz, zip, or ar")
+	level := flag.Int("level", 0, "compressor level, format-dependent (0 means the format's default)")
+	concurrency := flag.Int("concurrency", 4, "maximum number of files archived at once")
+	config := flag.String("config", "", "path to a JSON file describing multiple globs and per-glob policies")
+	dedup := flag.String("dedup", "", "content-addressed chunk store directory")
+	restore := flag.Bool("restore", false, "reassemble a -dedup archive: myRotate -dedup DIR -restore archive dest")
 	flag.Parse()
 
-	// Ensure the destination directory is valid
-	destInfo, err := os.Stat(*dest)
-	CheckErr(err)
-	if !destInfo.IsDir() {
-		fmt.Fprintf(os.Stderr, "%s is not a directory\n", *dest)
+	if *restore {
+		if *dedup == "" {
+			panic("-restore requires -dedup DIR to locate the chunk store")
+		}
+		args := flag.Args()
+		if len(args) != 2 {
+			panic("-restore requires exactly two arguments: archive dest")
+		}
+		CheckErr(restoreDedup(args[0], args[1], NewChunkStore(*dedup)))
 		return
 	}
 
-	// Get the list of files to archive
-	var files []string
-	if *dest == "" {
-		files = os.Args[1:]
-	} else {
-		files = os.Args[3:]
+	if *config != "" {
+		runConfig(*config)
+		return
 	}
 
-	// Check if files are provided
+	if *dest != "" {
+		destInfo, err := os.Stat(*dest)
+		CheckErr(err)
+		if !destInfo.IsDir() {
+			fmt.Fprintf(os.Stderr, "%s is not a directory\n", *dest)
+			return
+		}
+	}
+
+	files := flag.Args()
 	if len(files) < 1 {
 		panic("No files provided for archiving")
 	}
 
-	// Archive each file concurrently
-	wg := new(sync.WaitGroup)
-	for _, path := range files {
-		wg.Add(1)
-		go func(path string) {
-			defer wg.Done()
-			info, err := os.Stat(path)
-			if err != nil {
-				fmt.Fprintln(os.Stderr, err)
-				return
-			}
+	var maxSize int64
+	if *sizeFlag != "" {
+		var err error
+		maxSize, err = parseSize(*sizeFlag)
+		CheckErr(err)
+	}
 
-			name, ok := getArchiveName(&info)
-			if !ok {
-				return
-			}
+	var maxAge time.Duration
+	if *ageFlag != "" {
+		var err error
+		maxAge, err = time.ParseDuration(*ageFlag)
+		CheckErr(err)
+	}
 
-			res, err := os.Create(name)
-			if err != nil {
-				fmt.Fprintln(os.Stderr, err)
-				return
-			}
-			defer res.Close()
-			fmt.Println("CREATED:", name)
+	rotator := &Rotator{
+		Dest:        *dest,
+		MaxSize:     maxSize,
+		MaxAge:      maxAge,
+		Keep:        *keep,
+		Truncate:    *truncate,
+		Format:      *format,
+		Level:       *level,
+		Concurrency: *concurrency,
+	}
+	if *dedup != "" {
+		rotator.Dedup = NewChunkStore(*dedup)
+	}
+	rotator.RotateAll(files)
+}
 
-			err = fillArchive(&path, res, &info)
-			if err != nil {
-				fmt.Fprintln(os.Stderr, err)
-				removeArchive(&name)
-				return
-			}
+// runConfig rotates every glob described in a -config file, each with its
+// own Rotator built from its policy.
+func runConfig(path string) {
+	cfg, err := loadConfig(path)
+	CheckErr(err)
 
-			if *dest != "" {
-				moveArchive(&name, dest)
-			}
-		}(path)
+	for _, p := range cfg.Globs {
+		rotator, err := rotatorFor(p)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+
+		matches, err := filepath.Glob(p.Pattern)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+
+		rotator.RotateAll(matches)
 	}
-	wg.Wait()
 }
 
-// getArchiveName generates the name for the compressed tar archive based on file information.
-func getArchiveName(info *fs.FileInfo) (string, bool) {
-	stamp := (*info).ModTime().Unix()
-	name, found := strings.CutSuffix((*info).Name(), ".log")
+// getArchiveName generates the name for the archive based on file
+// information and the format's extension.
+func getArchiveName(path string, info fs.FileInfo, ext string) (string, bool) {
+	stamp := info.ModTime().Unix()
+	name, found := strings.CutSuffix(info.Name(), ".log")
 	if !found {
-		fmt.Fprintln(os.Stderr, "Wrong file format, only .log accepted")
 		return "", false
 	}
-	name = fmt.Sprintf("%s_%d.tar.gz", name, stamp)
-	return name, true
+	name = fmt.Sprintf("%s_%d%s", name, stamp, ext)
+	return filepath.Join(filepath.Dir(path), name), true
 }
 
-// moveArchive moves the created archive to the destination directory.
-func moveArchive(name, dest *string) {
-	cmd := exec.Command("mv", *name, *dest)
-	stdout, err := cmd.CombinedOutput()
+// archiveBaseName strips the "_<unix><ext>" suffix myRotate gives its
+// archives, leaving the base name shared by every rotation of one log.
+func archiveBaseName(archiveName, ext string) string {
+	name := strings.TrimSuffix(archiveName, ext)
+	if idx := strings.LastIndex(name, "_"); idx != -1 {
+		if _, err := strconv.ParseInt(name[idx+1:], 10, 64); err == nil {
+			return name[:idx]
+		}
+	}
+	return name
+}
+
+// moveArchive moves the created archive to the destination directory,
+// falling back to a copy-and-remove when the rename fails across devices.
+func moveArchive(name, dest string) error {
+	target := filepath.Join(dest, filepath.Base(name))
+	if err := os.Rename(name, target); err == nil {
+		return nil
+	}
+
+	src, err := os.Open(name)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		return
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return err
 	}
-	fmt.Print(string(stdout))
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	src.Close()
+	return os.Remove(name)
 }
 
-// removeArchive removes the archive if an error occurs during archiving.
-func removeArchive(name *string) {
-	cmd := exec.Command("rm", *name)
-	stdout, err := cmd.CombinedOutput()
+// truncateFile empties path in place, keeping the file (and its permissions)
+// around for the next write after it has been safely archived.
+func truncateFile(path string) error {
+	return os.Truncate(path, 0)
+}
+
+// pruneArchives deletes all but the keep most recent archives sharing base
+// in dir, ordering by the unix timestamp encoded in each archive's
+// "_<unix><ext>" suffix.
+func pruneArchives(dir, base, ext string, keep int) error {
+	entries, err := os.ReadDir(dir)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		return
+		return err
+	}
+
+	type archive struct {
+		name  string
+		stamp int64
+	}
+	var archives []archive
+	prefix := base + "_"
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		stampStr := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)
+		stamp, err := strconv.ParseInt(stampStr, 10, 64)
+		if err != nil {
+			continue
+		}
+		archives = append(archives, archive{name: name, stamp: stamp})
 	}
-	fmt.Print(string(stdout))
+
+	if len(archives) <= keep {
+		return nil
+	}
+
+	sort.Slice(archives, func(i, j int) bool { return archives[i].stamp > archives[j].stamp })
+	for _, a := range archives[keep:] {
+		if err := os.Remove(filepath.Join(dir, a.name)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+	return nil
 }
 
-// fillArchive creates the compressed tar archive and fills it with file contents.
-func fillArchive(path *string, buf io.Writer, info *fs.FileInfo) error {
-	gw := gzip.NewWriter(buf)
-	defer gw.Close()
+// Header is the subset of per-entry metadata every ArchiveWriter needs to
+// store a single file.
+type Header struct {
+	Name    string
+	Size    int64
+	Mode    int64
+	ModTime time.Time
+}
 
-	tw := tar.NewWriter(gw)
-	defer tw.Close()
+// ArchiveWriter writes one archived file through a header followed by its
+// contents, regardless of the underlying container format.
+type ArchiveWriter interface {
+	WriteHeader(hdr *Header) error
+	Write(p []byte) (int, error)
+	Close() error
+}
 
-	file, err := os.Open(*path)
+// extensionFor returns the archive file extension for a -format value.
+func extensionFor(format string) string {
+	switch format {
+	case "tarzst":
+		return ".tar.zst"
+	case "tarxz":
+		return ".tar.xz"
+	case "zip":
+		return ".zip"
+	case "ar":
+		return ".a"
+	default:
+		return ".tar.gz"
+	}
+}
+
+// newArchiveWriter builds the ArchiveWriter for a -format value, writing
+// into w. level is passed through to formats that support compressor
+// tuning; 0 means "use the format's default".
+func newArchiveWriter(format string, w io.Writer, level int) (ArchiveWriter, error) {
+	switch format {
+	case "", "targz":
+		return newTarGzipWriter(w, level)
+	case "tarzst":
+		return newTarZstdWriter(w, level)
+	case "tarxz":
+		if level != 0 {
+			fmt.Fprintln(os.Stderr, "myRotate: -level is not supported for tarxz, ignoring")
+		}
+		return newTarXzWriter(w)
+	case "zip":
+		return newZipArchiveWriter(w, level), nil
+	case "ar":
+		return newArWriter(w)
+	default:
+		return nil, fmt.Errorf("unknown archive format %q", format)
+	}
+}
+
+// tarArchiveWriter implements ArchiveWriter over archive/tar, fed through a
+// compressor (gzip, zstd or xz) that is closed after the tar trailer.
+type tarArchiveWriter struct {
+	comp io.WriteCloser
+	tw   *tar.Writer
+}
+
+func (t *tarArchiveWriter) WriteHeader(hdr *Header) error {
+	return t.tw.WriteHeader(&tar.Header{Name: hdr.Name, Size: hdr.Size, Mode: hdr.Mode, ModTime: hdr.ModTime})
+}
+
+func (t *tarArchiveWriter) Write(p []byte) (int, error) {
+	return t.tw.Write(p)
+}
+
+func (t *tarArchiveWriter) Close() error {
+	if err := t.tw.Close(); err != nil {
+		return err
+	}
+	return t.comp.Close()
+}
+
+// newTarGzipWriter builds the classic tar.gz ArchiveWriter.
+func newTarGzipWriter(w io.Writer, level int) (*tarArchiveWriter, error) {
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	gw, err := gzip.NewWriterLevel(w, level)
+	if err != nil {
+		return nil, err
+	}
+	return &tarArchiveWriter{comp: gw, tw: tar.NewWriter(gw)}, nil
+}
+
+// newTarZstdWriter builds a tar.zst ArchiveWriter.
+func newTarZstdWriter(w io.Writer, level int) (*tarArchiveWriter, error) {
+	var opts []zstd.EOption
+	if level > 0 {
+		opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+	}
+	zw, err := zstd.NewWriter(w, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &tarArchiveWriter{comp: zw, tw: tar.NewWriter(zw)}, nil
+}
+
+// newTarXzWriter builds a tar.xz ArchiveWriter.
+func newTarXzWriter(w io.Writer) (*tarArchiveWriter, error) {
+	xw, err := xz.NewWriter(w)
+	if err != nil {
+		return nil, err
+	}
+	return &tarArchiveWriter{comp: xw, tw: tar.NewWriter(xw)}, nil
+}
+
+// zipArchiveWriter implements ArchiveWriter over archive/zip.
+type zipArchiveWriter struct {
+	zw  *zip.Writer
+	cur io.Writer
+}
+
+// newZipArchiveWriter builds a zip ArchiveWriter. When level is non-zero, the
+// zip.Writer's deflate compressor is overridden to honor it (archive/zip
+// otherwise always writes at flate.DefaultCompression); the override is
+// scoped to this writer, not registered process-wide.
+func newZipArchiveWriter(w io.Writer, level int) *zipArchiveWriter {
+	zw := zip.NewWriter(w)
+	if level != 0 {
+		zw.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+			return flate.NewWriter(out, level)
+		})
+	}
+	return &zipArchiveWriter{zw: zw}
+}
+
+func (z *zipArchiveWriter) WriteHeader(hdr *Header) error {
+	fh := &zip.FileHeader{Name: hdr.Name, Modified: hdr.ModTime, Method: zip.Deflate}
+	fh.SetMode(fs.FileMode(hdr.Mode))
+	w, err := z.zw.CreateHeader(fh)
+	if err != nil {
+		return err
+	}
+	z.cur = w
+	return nil
+}
+
+func (z *zipArchiveWriter) Write(p []byte) (int, error) {
+	return z.cur.Write(p)
+}
+
+func (z *zipArchiveWriter) Close() error {
+	return z.zw.Close()
+}
+
+// arWriter implements ArchiveWriter as a classic Unix ar archive: a
+// "!<arch>\n" magic followed by one fixed-width 60-byte header per file and
+// its data, padded to an even length.
+type arWriter struct {
+	w        io.Writer
+	dataSize int64
+}
+
+// arMagic is the fixed magic string every ar archive starts with.
+const arMagic = "!<arch>\n"
+
+func newArWriter(w io.Writer) (*arWriter, error) {
+	if _, err := io.WriteString(w, arMagic); err != nil {
+		return nil, err
+	}
+	return &arWriter{w: w}, nil
+}
+
+func (a *arWriter) WriteHeader(hdr *Header) error {
+	name := hdr.Name
+	if len(name) > 16 {
+		name = name[:16]
+	}
+	// %-16s%-12d%-6d%-6d%-8o%-10d`\n: name, mtime, uid, gid, mode, size, magic
+	header := fmt.Sprintf("%-16s%-12d%-6d%-6d%-8o%-10d`\n", name, hdr.ModTime.Unix(), 0, 0, hdr.Mode, hdr.Size)
+	if _, err := io.WriteString(a.w, header); err != nil {
+		return err
+	}
+	a.dataSize = hdr.Size
+	return nil
+}
+
+func (a *arWriter) Write(p []byte) (int, error) {
+	return a.w.Write(p)
+}
+
+func (a *arWriter) Close() error {
+	if a.dataSize%2 != 0 {
+		_, err := a.w.Write([]byte{'\n'})
+		return err
+	}
+	return nil
+}
+
+// fillArchive writes path into buf through the ArchiveWriter selected by
+// format, using level for formats that support compressor tuning.
+func fillArchive(path string, buf io.Writer, info fs.FileInfo, format string, level int) error {
+	aw, err := newArchiveWriter(format, buf, level)
+	if err != nil {
+		return err
+	}
+	defer aw.Close()
+
+	file, err := os.Open(path)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
-	// create correct tar header
-	header, err := tar.FileInfoHeader(*info, (*info).Name())
+	if err := aw.WriteHeader(&Header{Name: info.Name(), Size: info.Size(), Mode: int64(info.Mode().Perm()), ModTime: info.ModTime()}); err != nil {
+		return err
+	}
+
+	_, err = io.Copy(aw, file)
+	return err
+}
+
+// chunkSize is the target chunk size -dedup splits files into before
+// hashing and storing them.
+const chunkSize = 1 << 20
+
+// chunkRef points at one chunk of a dedup'd file: where it sits in the
+// original file and the content-addressed store.
+type chunkRef struct {
+	Offset int64  `json:"offset"`
+	Length int64  `json:"length"`
+	SHA256 string `json:"sha256"`
+}
+
+// fileManifest is the self-describing contents of a -dedup archive: enough
+// to reassemble the original file purely from chunks in the store.
+type fileManifest struct {
+	Name   string     `json:"name"`
+	Size   int64      `json:"size"`
+	Chunks []chunkRef `json:"chunks"`
+}
+
+// manifestEntryName is the tar entry name a -dedup archive's single entry
+// is stored under.
+const manifestEntryName = "manifest.json"
+
+// ChunkStore is a content-addressed store of ~1MiB chunks, shared by every
+// rotation so identical content across rotations is only ever stored once.
+// It keeps a lazily-populated in-memory set of hashes it has already seen so
+// repeated rotations don't os.Stat every chunk, and a per-hash lock so
+// concurrent rotators writing different logs can safely share the store.
+type ChunkStore struct {
+	dir   string
+	seen  sync.Map // hash string -> struct{}
+	locks sync.Map // hash string -> *sync.Mutex
+}
+
+// NewChunkStore opens (without yet populating) a chunk store rooted at dir.
+func NewChunkStore(dir string) *ChunkStore {
+	return &ChunkStore{dir: dir}
+}
+
+// pathFor returns the two-level fanout path a chunk's hash is stored under:
+// DIR/sha256/aa/bb/<fullhash>.
+func (s *ChunkStore) pathFor(hash string) string {
+	return filepath.Join(s.dir, "sha256", hash[:2], hash[2:4], hash)
+}
+
+// lockFor returns the mutex guarding concurrent writers of the same chunk.
+func (s *ChunkStore) lockFor(hash string) *sync.Mutex {
+	l, _ := s.locks.LoadOrStore(hash, &sync.Mutex{})
+	return l.(*sync.Mutex)
+}
+
+// Has reports whether a chunk is already stored, consulting the in-memory
+// set before falling back to a stat.
+func (s *ChunkStore) Has(hash string) bool {
+	if _, ok := s.seen.Load(hash); ok {
+		return true
+	}
+	if _, err := os.Stat(s.pathFor(hash)); err == nil {
+		s.seen.Store(hash, struct{}{})
+		return true
+	}
+	return false
+}
+
+// Put stores data under hash if it isn't already present.
+func (s *ChunkStore) Put(hash string, data []byte) error {
+	lock := s.lockFor(hash)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if s.Has(hash) {
+		return nil
+	}
+
+	path := s.pathFor(hash)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	s.seen.Store(hash, struct{}{})
+	return nil
+}
+
+// Get reads a previously stored chunk back out.
+func (s *ChunkStore) Get(hash string) ([]byte, error) {
+	return os.ReadFile(s.pathFor(hash))
+}
+
+// buildManifest splits path into chunkSize-ish chunks, stores any the
+// ChunkStore doesn't already have, and returns the manifest describing how
+// to reassemble it.
+func buildManifest(path string, info fs.FileInfo, store *ChunkStore) (*fileManifest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	m := &fileManifest{Name: info.Name(), Size: info.Size()}
+	buf := make([]byte, chunkSize)
+	var offset int64
+	for {
+		n, err := io.ReadFull(file, buf)
+		if n > 0 {
+			sum := sha256.Sum256(buf[:n])
+			hash := hex.EncodeToString(sum[:])
+			if err := store.Put(hash, buf[:n]); err != nil {
+				return nil, err
+			}
+			m.Chunks = append(m.Chunks, chunkRef{Offset: offset, Length: int64(n), SHA256: hash})
+			offset += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// writeDedupArchive writes path's chunk manifest into buf as a single
+// uncompressed tar entry; the chunk bytes themselves live in store, not in
+// the archive.
+func writeDedupArchive(path string, buf io.Writer, info fs.FileInfo, store *ChunkStore) error {
+	m, err := buildManifest(path, info, store)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(buf)
+	defer tw.Close()
+
+	hdr := &tar.Header{Name: manifestEntryName, Size: int64(len(data)), Mode: 0o644, ModTime: info.ModTime()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = tw.Write(data)
+	return err
+}
+
+// restoreDedup reassembles a -dedup archive's original file at dest,
+// streaming each chunk back out of store and verifying its digest as it's
+// read, so a corrupted or tampered chunk is caught rather than silently
+// reproduced.
+func restoreDedup(archivePath, dest string, store *ChunkStore) error {
+	archive, err := os.Open(archivePath)
 	if err != nil {
 		return err
 	}
-	err = tw.WriteHeader(header)
+	defer archive.Close()
+
+	tr := tar.NewReader(archive)
+	hdr, err := tr.Next()
 	if err != nil {
 		return err
 	}
+	if hdr.Name != manifestEntryName {
+		return fmt.Errorf("%s: not a -dedup archive (missing %s entry)", archivePath, manifestEntryName)
+	}
 
-	// copy file contents
-	_, err = io.Copy(tw, file)
+	data, err := io.ReadAll(tr)
 	if err != nil {
 		return err
 	}
-	fmt.Println("ARCHIVED:", *path)
+	var m fileManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	for _, c := range m.Chunks {
+		chunk, err := store.Get(c.SHA256)
+		if err != nil {
+			return fmt.Errorf("chunk %s: %w", c.SHA256, err)
+		}
+		sum := sha256.Sum256(chunk)
+		if hex.EncodeToString(sum[:]) != c.SHA256 {
+			return fmt.Errorf("chunk %s: digest mismatch, store is corrupt", c.SHA256)
+		}
+		if _, err := out.Write(chunk); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -0,0 +1,39 @@
+//go:build mmap && unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// countMmap counts a file through a memory-mapped read rather than the
+// buffered streaming path, for the -mmap fast path on large files. Building
+// this path requires `go build -tags mmap` on a unix platform.
+func countMmap(path string) (Counts, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Counts{}, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return Counts{}, err
+	}
+	if info.Size() == 0 {
+		return Counts{}, nil
+	}
+
+	data, err := syscall.Mmap(int(file.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return Counts{}, fmt.Errorf("mmap %s: %w", path, err)
+	}
+	defer syscall.Munmap(data)
+
+	var c Counts
+	inWord := false
+	countChunk(data, &c, &inWord)
+	return c, nil
+}
@@ -0,0 +1,13 @@
+//go:build !(mmap && unix)
+
+package main
+
+import "fmt"
+
+// countMmap is the fallback used when myWc is built without the mmap build
+// tag (or on a non-unix platform); -mmap still works as a flag but falls
+// back to an error so callers know to rebuild with `-tags mmap` on unix
+// instead of silently getting the slow path.
+func countMmap(path string) (Counts, error) {
+	return Counts{}, fmt.Errorf("%s: myWc was built without mmap support (rebuild with -tags mmap on a unix platform)", path)
+}
@@ -1,31 +1,74 @@
 /*
 myWc is a utility similar to the `wc` command in Unix-like operating systems.
-It counts lines, words, and characters in text files.
+It counts lines, words, characters and bytes in text files.
+
+Every file is read through a single streaming pass over large fixed-size
+buffers, so all four counts are produced together in one read instead of the
+counts being mutually exclusive, and long lines no longer break counting the
+way bufio.Scanner's 64KiB token limit used to.
 
 Usage:
+
 	myWc [options] file1 [file2 ...]
 
 Options:
+
 	-l    Count lines
 	-w    Count words
 	-m    Count characters
+	-c    Count bytes
+	-mmap Use a memory-mapped fast path for files above 8MiB
 
-If no options are specified, words are counted by default.*/
-
+If no options are specified, lines, words and bytes are all counted, matching
+`wc`'s own default. When more than one file is given, a final "total" row is
+printed.
+*/
 package main
 
 import (
-	"bufio"
-	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
+	"unicode"
 	"unicode/utf8"
 )
 
-var mutex sync.Mutex
+// readBufSize is the size of the fixed buffers countStream reads into.
+const readBufSize = 256 * 1024
+
+// mmapThreshold is the minimum file size -mmap will actually memory-map;
+// smaller files aren't worth the syscall overhead.
+const mmapThreshold = 8 << 20
+
+// Counts holds the four tallies myWc can report for a file.
+type Counts struct {
+	Lines int64
+	Words int64
+	Runes int64
+	Bytes int64
+}
+
+// Add accumulates other into c, used to build the "total" row.
+func (c *Counts) Add(other Counts) {
+	c.Lines += other.Lines
+	c.Words += other.Words
+	c.Runes += other.Runes
+	c.Bytes += other.Bytes
+}
+
+// selection records which columns the user asked to see.
+type selection struct {
+	lines, words, chars, bytes bool
+}
+
+// none reports whether no column flag was given.
+func (s selection) none() bool {
+	return !s.lines && !s.words && !s.chars && !s.bytes
+}
 
 // main is the entry point of the program.
 func main() {
@@ -33,116 +76,194 @@ func main() {
 	lMode := flag.Bool("l", false, "Count lines")
 	wMode := flag.Bool("w", false, "Count words")
 	mMode := flag.Bool("m", false, "Count characters")
+	cMode := flag.Bool("c", false, "Count bytes")
+	useMmap := flag.Bool("mmap", false, "Use a memory-mapped fast path for files above 8MiB")
 	flag.Parse()
 
-	// Validate flags and determine the mode
-	mode, err := validateFlags(lMode, wMode, mMode)
-	CheckErr(err)
+	sel := selection{lines: *lMode, words: *wMode, chars: *mMode, bytes: *cMode}
+	if sel.none() {
+		sel = selection{lines: true, words: true, bytes: true}
+	}
 
 	// Extract filenames from command line arguments
-	if len(flag.Args()) < 1 {
+	paths := flag.Args()
+	if len(paths) < 1 {
 		panic("No files provided")
 	}
-	files := make(map[string]int)
-	for _, el := range flag.Args() {
-		files[el] = 0
-	}
 
-	// Process files concurrently
+	// Process files concurrently, each into its own slot so output order
+	// matches input order and duplicate filenames don't collide.
+	results := make([]fileResult, len(paths))
 	wg := new(sync.WaitGroup)
-	for path := range files {
+	for i, path := range paths {
 		wg.Add(1)
-		go processFile(path, mode, &files, wg)
+		go func(i int, path string) {
+			defer wg.Done()
+			counts, err := processFile(path, *useMmap)
+			results[i] = fileResult{path: path, counts: counts, err: err}
+		}(i, path)
 	}
 	wg.Wait()
 
 	// Print results
-	for key, val := range files {
-		fmt.Printf("%d\t%s\n", val, key)
+	var total Counts
+	var ok int
+	for _, r := range results {
+		if r.err != nil {
+			fmt.Fprintln(os.Stderr, r.err)
+			continue
+		}
+		fmt.Println(formatRow(sel, r.counts, r.path))
+		total.Add(r.counts)
+		ok++
+	}
+	if len(paths) > 1 && ok > 0 {
+		fmt.Println(formatRow(sel, total, "total"))
 	}
 }
 
-// handleFileErr outputs error message into Stderr and deletes invalid path from the map
-func handleFileErr(path *string, files *map[string]int, err error) {
-	fmt.Fprintln(os.Stderr, err)
-	delete(*files, *path)
+// fileResult is one file's outcome, kept in input order.
+type fileResult struct {
+	path   string
+	counts Counts
+	err    error
 }
 
-// processFile reads the file at the given path and counts lines, words, or characters based on the specified mode.
-func processFile(path string, mode int8, files *map[string]int, wg *sync.WaitGroup) {
-	defer wg.Done()
-	var res int
-	var err error
-
-	// Ensure the file path is valid
+// processFile counts lines, words, runes and bytes in the file at path,
+// taking the memory-mapped fast path when useMmap is set and the file is
+// large enough to be worth it.
+func processFile(path string, useMmap bool) (Counts, error) {
 	info, err := os.Stat(path)
 	if err != nil {
-		handleFileErr(&path, files, err)
-		return
+		return Counts{}, err
 	}
-
 	if !info.Mode().IsRegular() {
-		fmt.Fprintf(os.Stderr, "%s is not a file\n", path)
-		delete(*files, path)
-		return
+		return Counts{}, fmt.Errorf("%s is not a file", path)
+	}
+
+	if useMmap && info.Size() >= mmapThreshold {
+		return countMmap(path)
 	}
 
-	// Open the file
 	file, err := os.Open(path)
 	if err != nil {
-		handleFileErr(&path, files, err)
-		return
+		return Counts{}, err
 	}
 	defer file.Close()
 
-	// Read the file line by line and count based on the mode
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		switch mode {
-		case 3: // lines
-			res++
-		case 2: // words
-			res += len(strings.Fields(scanner.Text()))
-		case 1: // characters
-			res += utf8.RuneCountInString(scanner.Text()) + 1
+	return countStream(file)
+}
+
+// countStream reads r in readBufSize chunks via io.ReadFull and counts
+// lines, words, runes and bytes in a single pass, carrying any rune split
+// across a buffer boundary into the next chunk and tracking the in-word
+// state across chunks so word counts stay correct at every boundary.
+func countStream(r io.Reader) (Counts, error) {
+	buf := make([]byte, readBufSize)
+	var carry []byte
+	var c Counts
+	inWord := false
+
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			data := buf[:n]
+			if len(carry) > 0 {
+				data = append(carry, data...)
+				carry = nil
+			}
+
+			split := completeRuneSplit(data)
+			countChunk(data[:split], &c, &inWord)
+			if split < len(data) {
+				carry = append([]byte(nil), data[split:]...)
+			}
+		}
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return c, err
 		}
 	}
 
-	// Update the map with the result
-	mutex.Lock()
-	(*files)[path] = res
-	mutex.Unlock()
+	if len(carry) > 0 {
+		countChunk(carry, &c, &inWord)
+	}
+	return c, nil
 }
 
-// CheckErr is a utility function to panic if an error is not nil.
-func CheckErr(err error) {
-	if err != nil {
-		panic(err)
+// countChunk updates c with the lines/words/runes/bytes found in data,
+// which must contain only complete UTF-8 runes. inWord persists the
+// "currently inside a word" state across calls so word boundaries split
+// across chunks are still counted once.
+func countChunk(data []byte, c *Counts, inWord *bool) {
+	c.Bytes += int64(len(data))
+	for i := 0; i < len(data); {
+		r, size := utf8.DecodeRune(data[i:])
+		c.Runes++
+		if r == '\n' {
+			c.Lines++
+		}
+		if unicode.IsSpace(r) {
+			*inWord = false
+		} else if !*inWord {
+			c.Words++
+			*inWord = true
+		}
+		i += size
 	}
 }
 
-// validateFlags validates the command line flags and determines the mode of operation.
-func validateFlags(lMode, wMode, mMode *bool) (int8, error) {
-	// Count the number of flags that are true
-	var count, mode int8
-	if *lMode {
-		count++
-		mode = 3
+// completeRuneSplit returns the largest prefix length of data that holds
+// only complete UTF-8 runes, so a multi-byte rune straddling the end of a
+// read buffer can be carried whole into the next one instead of being
+// decoded as two broken runes.
+func completeRuneSplit(data []byte) int {
+	max := utf8.UTFMax - 1
+	if max > len(data) {
+		max = len(data)
 	}
-	if *wMode {
-		count++
-		mode = 2
+	for i := 1; i <= max; i++ {
+		b := data[len(data)-i]
+		if b < 0x80 {
+			return len(data)
+		}
+		if b >= 0xC0 {
+			if _, size := utf8.DecodeRune(data[len(data)-i:]); size == 1 {
+				return len(data) - i
+			}
+			return len(data)
+		}
+		// continuation byte (0x80-0xBF): keep walking back to find the lead byte
+	}
+	return len(data) - max
+}
+
+// formatRow renders one output line in wc's column order: lines, words,
+// chars, bytes, then the filename, tab-separated.
+func formatRow(sel selection, c Counts, name string) string {
+	var cols []string
+	if sel.lines {
+		cols = append(cols, strconv.FormatInt(c.Lines, 10))
+	}
+	if sel.words {
+		cols = append(cols, strconv.FormatInt(c.Words, 10))
 	}
-	if *mMode {
-		count++
-		mode = 1
+	if sel.chars {
+		cols = append(cols, strconv.FormatInt(c.Runes, 10))
 	}
+	if sel.bytes {
+		cols = append(cols, strconv.FormatInt(c.Bytes, 10))
+	}
+	cols = append(cols, name)
+	return strings.Join(cols, "\t")
+}
 
-	// Check if more than one flag is true
-	if count > 1 {
-		return 0, errors.New("Only one of -l, -w, -m can be specified")
-	} else if count == 0 {
-		mode = 2
+// CheckErr is a utility function to check for and handle errors.
+func CheckErr(err error) {
+	if err != nil {
+		panic(err)
 	}
-	return mode, nil
 }
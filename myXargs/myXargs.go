@@ -1,33 +1,234 @@
 /*
 myXargs is a simple command-line utility similar to xargs.
-It reads input from both command-line arguments and standard input,
-and then executes a specified command with those arguments.
+It reads records from standard input (or a file) and builds one or more
+invocations of a command from them, instead of naively appending every line
+to os.Args and shelling out once.
+
+Usage:
+
+	myXargs [options] command [initial-args...]
+
+Options:
+
+	-n N       Max number of input records per invocation
+	-P N       Run up to N invocations in parallel (default 1)
+	-I {}      Replace-string: run one invocation per input record, with every
+	           occurrence of the string substituted by that record
+	-0         Input records are NUL-separated instead of newline-separated
+	-a FILE    Read records from FILE instead of stdin
+	-d CHAR    Use CHAR as the input delimiter instead of newline
+	-s N       Max total command-line length in bytes per invocation; batches
+	           are split to stay under it
+
+Commands are executed directly via os/exec with an explicit argument list,
+never through a shell, so record contents can never be interpreted as shell
+syntax.
 */
 package main
 
 import (
 	"bufio"
+	"bytes"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"strings"
+	"sync"
 )
 
+// runner bounds how many child commands run at once and tracks the worst
+// (highest) exit code across them, which myXargs propagates as its own.
+type runner struct {
+	sem   chan struct{}
+	wg    sync.WaitGroup
+	mu    sync.Mutex
+	worst int
+}
+
+// newRunner builds a runner that allows at most parallel commands to run at
+// once; parallel below 1 is treated as 1 (sequential).
+func newRunner(parallel int) *runner {
+	if parallel < 1 {
+		parallel = 1
+	}
+	return &runner{sem: make(chan struct{}, parallel)}
+}
+
+// run launches args as a child command, bounded by the runner's concurrency
+// limit, and folds its exit code into the runner's worst-seen code.
+func (r *runner) run(args []string) {
+	r.wg.Add(1)
+	r.sem <- struct{}{}
+	go func() {
+		defer r.wg.Done()
+		defer func() { <-r.sem }()
+		code := runCommand(args)
+		r.mu.Lock()
+		if code > r.worst {
+			r.worst = code
+		}
+		r.mu.Unlock()
+	}()
+}
+
+// wait blocks until every launched command has finished and returns the
+// worst exit code seen.
+func (r *runner) wait() int {
+	r.wg.Wait()
+	return r.worst
+}
+
+// runCommand executes args[0] with args[1:], streaming its stdout/stderr
+// straight through rather than buffering, and returns its exit code.
+func runCommand(args []string) int {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return exitErr.ExitCode()
+		}
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// splitOn returns a bufio.SplitFunc that tokenizes on a single delimiter
+// byte, the way bufio.ScanLines does for '\n' but for any -d/-0 delimiter.
+func splitOn(delim byte) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+		if i := bytes.IndexByte(data, delim); i >= 0 {
+			return i + 1, data[:i], nil
+		}
+		if atEOF {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
+
+// streamRecords reads delimiter-separated records out of in and streams
+// them on the returned channel as they're found, instead of reading
+// everything into memory up front.
+func streamRecords(in io.Reader, delim byte) <-chan string {
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(make([]byte, 64*1024), 1<<20)
+		scanner.Split(splitOn(delim))
+		for scanner.Scan() {
+			out <- scanner.Text()
+		}
+		if err := scanner.Err(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}()
+	return out
+}
+
+// substitute copies template, replacing every occurrence of placeholder in
+// each argument with record.
+func substitute(template []string, placeholder, record string) []string {
+	args := make([]string, len(template))
+	for i, a := range template {
+		args[i] = strings.ReplaceAll(a, placeholder, record)
+	}
+	return args
+}
+
+// runPerRecord implements -I: one invocation per input record, with the
+// placeholder substituted into the command template.
+func runPerRecord(template []string, placeholder string, records <-chan string, parallel int) int {
+	r := newRunner(parallel)
+	for record := range records {
+		r.run(substitute(template, placeholder, record))
+	}
+	return r.wait()
+}
+
+// runBatched implements the default (non -I) mode: records are grouped into
+// batches appended after the command template, one invocation per batch.
+// A batch is flushed once it reaches maxArgs records (if maxArgs > 0) or
+// would push the command line past maxLen bytes (if maxLen > 0).
+func runBatched(template []string, records <-chan string, maxArgs, maxLen, parallel int) int {
+	r := newRunner(parallel)
+
+	templateLen := 0
+	for _, a := range template {
+		templateLen += len(a) + 1
+	}
+
+	var batch []string
+	batchLen := templateLen
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		r.run(append(append([]string{}, template...), batch...))
+		batch = nil
+		batchLen = templateLen
+	}
+
+	for record := range records {
+		recLen := len(record) + 1
+		if len(batch) > 0 && ((maxArgs > 0 && len(batch) >= maxArgs) || (maxLen > 0 && batchLen+recLen > maxLen)) {
+			flush()
+		}
+		batch = append(batch, record)
+		batchLen += recLen
+	}
+	flush()
+
+	return r.wait()
+}
+
 func main() {
-	arr := os.Args[1:]
+	n := flag.Int("n", 0, "max number of input records per invocation")
+	parallel := flag.Int("P", 1, "run up to N invocations in parallel")
+	replace := flag.String("I", "", "replace-string: run one invocation per input record with it substituted")
+	nullSep := flag.Bool("0", false, "input records are NUL-separated")
+	aFile := flag.String("a", "", "read records from FILE instead of stdin")
+	delimFlag := flag.String("d", "", "use this character as the input delimiter instead of newline")
+	maxLen := flag.Int("s", 0, "max total command-line length in bytes per invocation")
+	flag.Parse()
 
-	// Read command-line arguments
-	scanner := bufio.NewScanner(os.Stdin)
-	for scanner.Scan() {
-		arr = append(arr, scanner.Text())
+	template := flag.Args()
+	if len(template) < 1 {
+		panic("No command provided")
 	}
-	err := scanner.Err()
-	CheckErr(err)
 
-	// Execute the command with arguments
-	cmd := exec.Command(arr[0], arr[1:]...)
-	stdout, err := cmd.CombinedOutput()
-	CheckErr(err)
-	fmt.Print(string(stdout))
+	delim := byte('\n')
+	if *nullSep {
+		delim = 0
+	}
+	if *delimFlag != "" {
+		delim = (*delimFlag)[0]
+	}
+
+	in := io.Reader(os.Stdin)
+	if *aFile != "" {
+		f, err := os.Open(*aFile)
+		CheckErr(err)
+		defer f.Close()
+		in = f
+	}
+
+	records := streamRecords(in, delim)
+
+	var code int
+	if *replace != "" {
+		code = runPerRecord(template, *replace, records, *parallel)
+	} else {
+		code = runBatched(template, records, *n, *maxLen, *parallel)
+	}
+	os.Exit(code)
 }
 
 // CheckErr is a utility function to check for and handle errors.